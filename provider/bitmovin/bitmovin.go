@@ -4,15 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/NYTimes/video-transcoding-api/db"
 	"github.com/NYTimes/video-transcoding-api/provider"
-	"github.com/bitmovin/bitmovin-go/bitmovin"
 	"github.com/bitmovin/bitmovin-go/bitmovintypes"
 	"github.com/bitmovin/bitmovin-go/models"
-	"github.com/bitmovin/bitmovin-go/services"
 	"github.com/bitmovin/video-transcoding-api/config"
 )
 
@@ -39,6 +38,21 @@ var h264Levels = []bitmovintypes.H264Level{
 	bitmovintypes.H264Level5_1,
 	bitmovintypes.H264Level5_2}
 
+var h265Levels = []bitmovintypes.H265Level{
+	bitmovintypes.H265Level1,
+	bitmovintypes.H265Level2,
+	bitmovintypes.H265Level2_1,
+	bitmovintypes.H265Level3,
+	bitmovintypes.H265Level3_1,
+	bitmovintypes.H265Level4,
+	bitmovintypes.H265Level4_1,
+	bitmovintypes.H265Level5,
+	bitmovintypes.H265Level5_1,
+	bitmovintypes.H265Level5_2,
+	bitmovintypes.H265Level6,
+	bitmovintypes.H265Level6_1,
+	bitmovintypes.H265Level6_2}
+
 var errBitmovinInvalidConfig = provider.InvalidConfigError("missing Bitmovin api key. Please define the environment variable BITMOVIN_API_KEY set this value in the configuration file")
 
 var s3UrlCloudRegionMap = map[string]bitmovintypes.AWSCloudRegion{
@@ -70,83 +84,143 @@ var s3UrlCloudRegionMap = map[string]bitmovintypes.AWSCloudRegion{
 	"s3.dualstack.sa-east-1.amazonaws.com":      bitmovintypes.AWSCloudRegionSAEast1,
 }
 
+// h264PresetPrefix and h265PresetPrefix are prepended by CreatePreset to the
+// preset ID it returns, recording which codec configuration service
+// (H264 or H265) holds the preset.
+const (
+	h264PresetPrefix = "h264:"
+	h265PresetPrefix = "h265:"
+)
+
 type bitmovinProvider struct {
-	client *bitmovin.Bitmovin
+	client bitmovinClient
 	config *config.Bitmovin
 }
 
 type bitmovinPreset struct {
-	Video models.H264CodecConfiguration
+	// Video holds either a models.H264CodecConfiguration or a
+	// models.H265CodecConfiguration, depending on which codec the preset
+	// was created with.
+	Video interface{}
 	Audio models.AACCodecConfiguration
 }
 
+// manifestRendition carries the bits of a single video rendition that the
+// HLS/DASH manifests need in order to describe it as a variant stream or
+// representation. Renditions round-trip through Bitmovin as encoding
+// CustomData (see SetEncodingCustomData/EncodingCustomData), so the fields
+// must be exported and tagged to survive that JSON marshaling.
+type manifestRendition struct {
+	TSMuxingID   string `json:"tsMuxingId"`
+	FMP4MuxingID string `json:"fmp4MuxingId"`
+	Bitrate      int64  `json:"bitrate"`
+	Width        int64  `json:"width"`
+	Height       int64  `json:"height"`
+}
+
+// decodeManifestRenditions converts the "manifestRenditions" entry read back
+// from an encoding's CustomData into []manifestRendition. Custom data always
+// comes back from the Bitmovin client as generic JSON values - []interface{}
+// of map[string]interface{} - never as the concrete type it was stored with,
+// so a plain type assertion to []manifestRendition always fails silently.
+func decodeManifestRenditions(raw interface{}) []manifestRendition {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	renditions := make([]manifestRendition, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		renditions = append(renditions, manifestRendition{
+			TSMuxingID:   stringFromCustomData(m, "tsMuxingId"),
+			FMP4MuxingID: stringFromCustomData(m, "fmp4MuxingId"),
+			Bitrate:      int64FromCustomData(m, "bitrate"),
+			Width:        int64FromCustomData(m, "width"),
+			Height:       int64FromCustomData(m, "height"),
+		})
+	}
+	return renditions
+}
+
+func stringFromCustomData(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// int64FromCustomData reads an integer field out of decoded custom data.
+// encoding/json decodes all JSON numbers into interface{} as float64, even
+// though the value was an int64 when it was stored.
+func int64FromCustomData(m map[string]interface{}, key string) int64 {
+	f, _ := m[key].(float64)
+	return int64(f)
+}
+
+// protocolsInclude reports whether protocol (e.g. "hls", "dash") is present
+// in a job's streaming_params.protocol list.
+func protocolsInclude(protocols []string, protocol string) bool {
+	for _, p := range protocols {
+		if strings.EqualFold(p, protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+func derefInt(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
 func (p *bitmovinProvider) CreatePreset(preset db.Preset) (string, error) {
 	//Find a corresponding audio configuration that lines up, otherwise create it
 	if strings.ToLower(preset.Audio.Codec) != "aac" {
 		return "", fmt.Errorf("Unsupported Audio codec: %v", preset.Audio.Codec)
 	}
-	// Bitmovin supports H.264 and H.265, H.265 support can be added in the future
-	if strings.ToLower(preset.Video.Codec) != "h264" {
+	videoCodec := strings.ToLower(preset.Video.Codec)
+	if videoCodec != "h264" && videoCodec != "h265" {
 		return "", fmt.Errorf("Unsupported Video codec: %v", preset.Video.Codec)
 	}
 
-	aac := services.NewAACCodecConfigurationService(p.client)
-	response, err := aac.List(0, 1)
+	bitrate, err := strconv.Atoi(preset.Audio.Bitrate)
 	if err != nil {
 		return "", err
 	}
-	if response.Status == "ERROR" {
-		return "", errors.New("")
-	}
-	totalCount := *response.Data.Result.TotalCount
-	response, err = aac.List(0, totalCount-1)
+	audioConfigID, err := p.client.FindOrCreateAACConfig(int64(bitrate))
 	if err != nil {
 		return "", err
 	}
-	if response.Status == "ERROR" {
-		return "", errors.New("")
-	}
-	var audioConfigID string
-	audioConfigs := response.Data.Result.Items
-	bitrate, err := strconv.Atoi(preset.Audio.Bitrate)
-	if err != nil {
-		return "", err
-	}
-	for _, c := range audioConfigs {
-		if *c.Bitrate == int64(bitrate) {
-			audioConfigID = *c.ID
-			break
-		}
-	}
-	if audioConfigID == "" {
-		temp := int64(bitrate)
-		audioConfig := &models.AACCodecConfiguration{
-			Bitrate:      &temp,
-			SamplingRate: floatToPtr(48000.0),
-		}
-		resp, err := aac.Create(audioConfig)
+
+	//Create Video and add Custom Data element to point to the audio config.
+	customData := map[string]interface{}{"audio": audioConfigID}
+
+	if videoCodec == "h265" {
+		h265Config, err := p.createH265VideoPreset(preset)
 		if err != nil {
 			return "", err
 		}
-		if resp.Status == "ERROR" {
-			return "", errors.New("")
+		h265Config.CustomData = customData
+		id, err := p.client.CreateH265Config(h265Config)
+		if err != nil {
+			return "", err
 		}
-		audioConfigID = *resp.Data.Result.ID
+		return h265PresetPrefix + id, nil
 	}
-	//Create Video and add Custom Data element to point to the
-	customData := make(map[string]interface{})
-	customData["audio"] = audioConfigID
+
 	h264Config, err := p.createVideoPreset(preset)
-	h264Config.CustomData = customData
-	h264 := services.NewH264CodecConfigurationService(p.client)
-	respo, err := h264.Create(h264Config)
 	if err != nil {
 		return "", err
 	}
-	if respo.Status == "ERROR" {
-		return "", errors.New("")
+	h264Config.CustomData = customData
+	id, err := p.client.CreateH264Config(h264Config)
+	if err != nil {
+		return "", err
 	}
-	return *respo.Data.Result.ID, nil
+	return h264PresetPrefix + id, nil
 }
 
 func (p *bitmovinProvider) createVideoPreset(preset db.Preset) (*models.H264CodecConfiguration, error) {
@@ -209,82 +283,407 @@ func (p *bitmovinProvider) createVideoPreset(preset db.Preset) (*models.H264Code
 	return h264, nil
 }
 
-func (p *bitmovinProvider) DeletePreset(presetID string) error {
-	// Only delete the video preset, leave the audio preset.
-	h264 := services.NewH264CodecConfigurationService(p.client)
-	response, err := h264.Delete(presetID)
+// hevcProfiles maps the lowercase profile names accepted in db.Preset onto
+// Bitmovin's HEVC profile enum.
+var hevcProfiles = map[string]bitmovintypes.H265Profile{
+	"main":             bitmovintypes.H265ProfileMain,
+	"main10":           bitmovintypes.H265ProfileMain10,
+	"mainstillpicture": bitmovintypes.H265ProfileMainStillPicture,
+	"":                 bitmovintypes.H265ProfileMain,
+}
+
+func (p *bitmovinProvider) createH265VideoPreset(preset db.Preset) (*models.H265CodecConfiguration, error) {
+	h265 := &models.H265CodecConfiguration{}
+	profile, ok := hevcProfiles[strings.ToLower(preset.Video.Profile)]
+	if !ok {
+		return nil, fmt.Errorf("Unrecognized H265 Profile: %v", preset.Video.Profile)
+	}
+	h265.Profile = profile
+
+	foundLevel := false
+	for _, l := range h265Levels {
+		if l == bitmovintypes.H265Level(preset.Video.ProfileLevel) {
+			h265.Level = l
+			foundLevel = true
+			break
+		}
+	}
+	if !foundLevel {
+		return nil, fmt.Errorf("Unrecognized H265 Level: %v", preset.Video.ProfileLevel)
+	}
+	if preset.Video.Width != "" {
+		width, err := strconv.Atoi(preset.Video.Width)
+		if err != nil {
+			return nil, err
+		}
+		h265.Width = intToPtr(int64(width))
+	}
+	if preset.Video.Height != "" {
+		height, err := strconv.Atoi(preset.Video.Height)
+		if err != nil {
+			return nil, err
+		}
+		h265.Height = intToPtr(int64(height))
+	}
+
+	if preset.Video.Bitrate == "" {
+		return nil, errors.New("Video Bitrate must be set")
+	}
+	bitrate, err := strconv.Atoi(preset.Video.Bitrate)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if response.Status == "ERROR" {
-		return errors.New("")
+	h265.Bitrate = intToPtr(int64(bitrate))
+	if preset.Video.GopSize != "" {
+		gopSize, err := strconv.Atoi(preset.Video.GopSize)
+		if err != nil {
+			return nil, err
+		}
+		h265.MaxGOP = intToPtr(int64(gopSize))
 	}
-	return nil
+
+	return h265, nil
+}
+
+// splitPresetID separates the "h264:"/"h265:" prefix CreatePreset stores off
+// a preset ID so DeletePreset/GetPreset know which codec service to use.
+func splitPresetID(presetID string) (codec, id string) {
+	switch {
+	case strings.HasPrefix(presetID, h264PresetPrefix):
+		return "h264", strings.TrimPrefix(presetID, h264PresetPrefix)
+	case strings.HasPrefix(presetID, h265PresetPrefix):
+		return "h265", strings.TrimPrefix(presetID, h265PresetPrefix)
+	default:
+		// Presets created before the h264:/h265: prefix was introduced.
+		return "h264", presetID
+	}
+}
+
+// resolveVideoPreset retrieves a stored video preset, dispatching to the
+// H264 or H265 configuration service based on its prefix, and returns the
+// video config ID (without prefix), its associated audio config ID, and the
+// rendition's bitrate/width/height for manifest generation.
+func (p *bitmovinProvider) resolveVideoPreset(presetID string) (videoConfigID, audioConfigID string, bitrate, width, height int64, err error) {
+	codec, id := splitPresetID(presetID)
+	var customData map[string]interface{}
+	if codec == "h265" {
+		cfg, err := p.client.RetrieveH265Config(id)
+		if err != nil {
+			return "", "", 0, 0, 0, err
+		}
+		customData = cfg.CustomData
+		bitrate = derefInt(cfg.Bitrate)
+		width = derefInt(cfg.Width)
+		height = derefInt(cfg.Height)
+	} else {
+		cfg, err := p.client.RetrieveH264Config(id)
+		if err != nil {
+			return "", "", 0, 0, 0, err
+		}
+		customData = cfg.CustomData
+		bitrate = derefInt(cfg.Bitrate)
+		width = derefInt(cfg.Width)
+		height = derefInt(cfg.Height)
+	}
+	i, ok := customData["audio"]
+	if !ok {
+		return "", "", 0, 0, 0, errors.New("No Audio configuration found for Video Preset")
+	}
+	audioConfigID, ok = i.(string)
+	if !ok {
+		return "", "", 0, 0, 0, errors.New("Audio Configuration somehow not a string")
+	}
+	return id, audioConfigID, bitrate, width, height, nil
+}
+
+func (p *bitmovinProvider) DeletePreset(presetID string) error {
+	// Only delete the video preset, leave the audio preset.
+	codec, id := splitPresetID(presetID)
+	if codec == "h265" {
+		return p.client.DeleteH265Config(id)
+	}
+	return p.client.DeleteH264Config(id)
 }
 
 func (p *bitmovinProvider) GetPreset(presetID string) (interface{}, error) {
-	// Return a custom struct with the H264 and AAC config?
-	h264 := services.NewH264CodecConfigurationService(p.client)
-	response, err := h264.Retrieve(presetID)
+	codec, id := splitPresetID(presetID)
+	var audioConfigID string
+	var preset bitmovinPreset
+	if codec == "h265" {
+		h265Config, err := p.client.RetrieveH265Config(id)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := h265Config.CustomData["audio"]
+		if !ok {
+			return nil, errors.New("No Audio configuration found for Video Preset")
+		}
+		audioConfigID, ok = i.(string)
+		if !ok {
+			return nil, errors.New("Audio Configuration somehow not a string")
+		}
+		preset.Video = *h265Config
+	} else {
+		h264Config, err := p.client.RetrieveH264Config(id)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := h264Config.CustomData["audio"]
+		if !ok {
+			return nil, errors.New("No Audio configuration found for Video Preset")
+		}
+		audioConfigID, ok = i.(string)
+		if !ok {
+			return nil, errors.New("Audio Configuration somehow not a string")
+		}
+		preset.Video = *h264Config
+	}
+
+	audioConfig, err := p.client.RetrieveAACConfig(audioConfigID)
 	if err != nil {
 		return nil, err
 	}
-	if response.Status == "ERROR" {
-		return nil, errors.New("")
+	preset.Audio = *audioConfig
+	return preset, nil
+}
+
+func (p *bitmovinProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+	// Parse the input, it will be an s3 url so need to parse out the
+	// region and the bucket name.
+	inputLoc, err := parseS3URL(job.Input, p.config.S3CompatibleEndpoints)
+	if err != nil {
+		return nil, err
 	}
-	h264Config := response.Data.Result
-	i, ok := h264Config.CustomData["audio"]
-	if !ok {
-		return nil, errors.New("No Audio configuration found for Video Preset")
+	inputID, err := p.client.CreateS3Input(inputLoc)
+	if err != nil {
+		return nil, err
 	}
-	s, ok := i.(string)
-	if !ok {
-		return nil, errors.New("Audio Configuration somehow not a string")
+
+	destLoc, err := parseS3URL(job.Destination, p.config.S3CompatibleEndpoints)
+	if err != nil {
+		return nil, err
 	}
-	aac := services.NewAACCodecConfigurationService(p.client)
-	audioResponse, err := aac.Retrieve(s)
+	outputID, err := p.client.CreateS3Output(destLoc)
 	if err != nil {
 		return nil, err
 	}
-	if audioResponse.Status == "ERROR" {
-		return nil, errors.New("")
+	destPath := destLoc.Directory
+
+	encodingID, err := p.client.CreateEncoding(job.ID)
+	if err != nil {
+		return nil, err
 	}
-	aacConfig := audioResponse.Data.Result
-	preset := bitmovinPreset{
-		Video: h264Config,
-		Audio: aacConfig,
+
+	wantsHLS := protocolsInclude(job.StreamingParams.Protocols, "hls")
+	wantsDASH := protocolsInclude(job.StreamingParams.Protocols, "dash")
+	var renditions []manifestRendition
+	var audioFMP4MuxingID string
+	for _, output := range job.Outputs {
+		// output.Preset is keyed by provider name (db.PresetMap) since the
+		// same job output can carry a different preset ID per provider.
+		videoConfigID, audioConfigID, bitrate, width, height, err := p.resolveVideoPreset(output.Preset[Name])
+		if err != nil {
+			return nil, err
+		}
+
+		videoStreamID, err := p.client.CreateVideoStream(encodingID, inputID, inputLoc.Key(), videoConfigID)
+		if err != nil {
+			return nil, err
+		}
+		audioStreamID, err := p.client.CreateAudioStream(encodingID, inputID, inputLoc.Key(), audioConfigID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.client.CreateMP4Muxing(encodingID, output.FileName, outputID, destPath, []string{videoStreamID, audioStreamID})
+		if err != nil {
+			return nil, err
+		}
+
+		if !wantsHLS && !wantsDASH {
+			continue
+		}
+
+		renditionPath := destPath + "/" + strings.TrimSuffix(output.FileName, filepath.Ext(output.FileName))
+		rendition := manifestRendition{Bitrate: bitrate, Width: width, Height: height}
+		if wantsHLS {
+			rendition.TSMuxingID, err = p.client.CreateTSMuxing(encodingID, videoStreamID, outputID, renditionPath, job.StreamingParams.HLSSegmentDuration)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if wantsDASH {
+			rendition.FMP4MuxingID, err = p.client.CreateFMP4Muxing(encodingID, videoStreamID, outputID, renditionPath, job.StreamingParams.HLSSegmentDuration)
+			if err != nil {
+				return nil, err
+			}
+		}
+		renditions = append(renditions, rendition)
+
+		if audioFMP4MuxingID == "" {
+			audioFMP4MuxingID, err = p.client.CreateFMP4Muxing(encodingID, audioStreamID, outputID, destPath+"/audio", job.StreamingParams.HLSSegmentDuration)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	return preset, errors.New("Not implemented")
+
+	// Stash the muxing IDs the manifests will reference as custom data on
+	// the encoding itself, the same trick used to associate an audio
+	// configuration with its video preset, so JobStatus can find them
+	// again once the encoding finishes.
+	if wantsHLS || wantsDASH {
+		err = p.client.SetEncodingCustomData(encodingID, map[string]interface{}{
+			"manifestRenditions": renditions,
+			"audioFMP4MuxingID":  audioFMP4MuxingID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.client.StartEncoding(encodingID); err != nil {
+		return nil, err
+	}
+
+	return &provider.JobStatus{
+		ProviderName:  Name,
+		ProviderJobID: encodingID,
+		Status:        provider.StatusQueued,
+	}, nil
 }
 
-func (p *bitmovinProvider) Transcode(*db.Job) (*provider.JobStatus, error) {
-	//Parse the input and set it up
-	//It will be an s3 url so need to parse out the region and the bucket name
+func (p *bitmovinProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+	status, progress, err := p.client.EncodingStatus(job.ProviderJobID)
+	if err != nil {
+		return nil, err
+	}
+	jobStatus := &provider.JobStatus{
+		ProviderName:   Name,
+		ProviderJobID:  job.ProviderJobID,
+		Status:         providerStatusFor(status),
+		StatusMessage:  string(status),
+		ProviderStatus: map[string]interface{}{"progress": progress},
+	}
+	if jobStatus.Status != provider.StatusFinished {
+		return jobStatus, nil
+	}
+
+	wantsHLS := protocolsInclude(job.StreamingParams.Protocols, "hls")
+	wantsDASH := protocolsInclude(job.StreamingParams.Protocols, "dash")
+	if !wantsHLS && !wantsDASH {
+		return jobStatus, nil
+	}
 
-	// Setup the streams and start transcoding
-	return nil, errors.New("Not implemented")
+	customData, err := p.client.EncodingCustomData(job.ProviderJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	playlistURL, _ := customData["playlistURL"].(string)
+	if playlistURL == "" {
+		playlistURL, err = p.generateManifests(job, customData, wantsHLS, wantsDASH)
+		if err != nil {
+			return nil, err
+		}
+		customData["playlistURL"] = playlistURL
+		if err := p.client.SetEncodingCustomData(job.ProviderJobID, customData); err != nil {
+			return nil, err
+		}
+	}
+	jobStatus.Output.Files = []provider.OutputFile{{Path: playlistURL, Container: manifestContainer(playlistURL)}}
+	return jobStatus, nil
+}
+
+// manifestContainer maps a generated manifest's extension onto the
+// container name JobStatus reports, since a DASH-only job's playlistURL
+// ends in .mpd rather than HLS's .m3u8.
+func manifestContainer(playlistURL string) string {
+	return strings.TrimPrefix(filepath.Ext(playlistURL), ".")
+}
+
+// generateManifests builds the HLS master playlist and/or the DASH MPD for
+// an already-finished encoding, blocking until Bitmovin reports the
+// manifests as generated, and returns the URL of the HLS master playlist
+// (or of the MPD, for a DASH-only job) under job.Destination. Callers
+// must only invoke this once per encoding (JobStatus guards on the
+// "playlistURL" custom data key) since Bitmovin doesn't dedupe manifest
+// creation and re-running it produces duplicate manifests.
+func (p *bitmovinProvider) generateManifests(job *db.Job, customData map[string]interface{}, wantsHLS, wantsDASH bool) (string, error) {
+	renditions := decodeManifestRenditions(customData["manifestRenditions"])
+	audioFMP4MuxingID, _ := customData["audioFMP4MuxingID"].(string)
+
+	destLoc, err := parseS3URL(job.Destination, p.config.S3CompatibleEndpoints)
+	if err != nil {
+		return "", err
+	}
+	destPath := destLoc.Directory
+	outputID, err := p.client.CreateS3Output(destLoc)
+	if err != nil {
+		return "", err
+	}
+
+	// The manifests are written to destPath, the directory job.Destination
+	// points into, so their URLs are job.Destination minus its last segment.
+	baseURL := strings.TrimSuffix(job.Destination, destLoc.FileName)
+
+	var playlistURL string
+	if wantsHLS {
+		manifestID, err := p.client.CreateHLSManifest(outputID, destPath, audioFMP4MuxingID, renditions)
+		if err != nil {
+			return "", err
+		}
+		if err := p.client.AwaitManifest(manifestID, manifestKindHLS); err != nil {
+			return "", err
+		}
+		playlistURL = baseURL + "master.m3u8"
+	}
+
+	if wantsDASH {
+		manifestID, err := p.client.CreateDASHManifest(outputID, destPath, audioFMP4MuxingID, renditions)
+		if err != nil {
+			return "", err
+		}
+		if err := p.client.AwaitManifest(manifestID, manifestKindDASH); err != nil {
+			return "", err
+		}
+		if playlistURL == "" {
+			playlistURL = baseURL + "stream.mpd"
+		}
+	}
+
+	return playlistURL, nil
 }
 
-func (p *bitmovinProvider) JobStatus(*db.Job) (*provider.JobStatus, error) {
-	// If the transcoding is finished, start manifest generation, wait (because it is fast),
-	// and then return done, otherwise send the status of the transcoding
-	return nil, errors.New("Not implemented")
+// providerStatusFor maps a Bitmovin encoding status onto the provider's own
+// Status type.
+func providerStatusFor(status bitmovintypes.Status) provider.Status {
+	switch status {
+	case bitmovintypes.StatusCreated, bitmovintypes.StatusQueued:
+		return provider.StatusQueued
+	case bitmovintypes.StatusRunning:
+		return provider.StatusStarted
+	case bitmovintypes.StatusFinished:
+		return provider.StatusFinished
+	case bitmovintypes.StatusError:
+		return provider.StatusFailed
+	default:
+		return provider.StatusStarted
+	}
 }
 
 func (p *bitmovinProvider) CancelJob(jobID string) error {
-	// stop the job
-	return errors.New("Not implemented")
+	return p.client.StopEncoding(jobID)
 }
 
 func (p *bitmovinProvider) Healthcheck() error {
-	// unknown
-	return errors.New("Not implemented")
+	return p.client.Healthcheck()
 }
 
 func (p *bitmovinProvider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
 		InputFormats:  []string{"prores", "h264"},
-		OutputFormats: []string{"mp4", "hls"},
+		OutputFormats: []string{"mp4", "hls", "hevc"},
 		Destinations:  []string{"s3"},
 	}
 }
@@ -293,24 +692,76 @@ func bitmovinFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
 	if cfg.Bitmovin.APIKey == "" {
 		return nil, errBitmovinInvalidConfig
 	}
-	client := bitmovin.NewBitmovin(cfg.Bitmovin.APIKey, cfg.Bitmovin.Endpoint, int64(cfg.Bitmovin.Timeout))
+	client, err := newBitmovinClient(cfg.Bitmovin)
+	if err != nil {
+		return nil, err
+	}
 	return &bitmovinProvider{client: client, config: cfg.Bitmovin}, nil
 }
 
-func parseS3URL(input string) (fileName string, bucketName string, cloudRegion bitmovintypes.AWSCloudRegion, err error) {
+// s3Location is what parseS3URL breaks an S3 (or S3-compatible) URL down
+// into. Callers branch on IsAWS to decide whether to build AWS-native
+// S3Input/S3Output resources or GenericS3Input/GenericS3Output ones.
+type s3Location struct {
+	FileName   string
+	BucketName string
+
+	// Directory is the key prefix between BucketName and FileName (empty
+	// for an object sitting directly under the bucket), used to root
+	// muxing/manifest output paths inside the destination.
+	Directory string
+
+	IsAWS       bool
+	CloudRegion bitmovintypes.AWSCloudRegion
+
+	// Populated instead of CloudRegion when the host isn't a recognised
+	// AWS endpoint but matches a configured S3-compatible one.
+	Endpoint        string
+	Region          string
+	AccessKeyEnvVar string
+	SecretKeyEnvVar string
+	PathStyle       bool
+	SSL             bool
+}
+
+// Key returns the full object key (Directory/FileName, or just FileName
+// when the object sits directly under the bucket), for use as an input
+// path that must be resolved relative to the bucket root.
+func (l *s3Location) Key() string {
+	if l.Directory == "" {
+		return l.FileName
+	}
+	return l.Directory + "/" + l.FileName
+}
+
+func parseS3URL(input string, genericEndpoints []config.S3CompatibleEndpoint) (*s3Location, error) {
 	u, err := url.Parse(input)
 	if err != nil {
-		return "", "", bitmovintypes.AWSCloudRegion(""), err
+		return nil, err
 	}
-	s := strings.Split(u.Path, "/")
-	fileName = s[len(s)-1]
-	bucketName = strings.TrimSuffix(u.Path, "/"+fileName)
-	bucketName = strings.TrimPrefix(bucketName, "/")
-	cloudRegion, ok := s3UrlCloudRegionMap[u.Host]
-	if !ok {
-		return "", "", bitmovintypes.AWSCloudRegion(""), fmt.Errorf("Unable to determine AWS Region from Host: %v", u.Host)
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	bucketName := segments[0]
+	fileName := segments[len(segments)-1]
+	directory := strings.Join(segments[1:len(segments)-1], "/")
+	loc := &s3Location{FileName: fileName, BucketName: bucketName, Directory: directory}
+
+	if cloudRegion, ok := s3UrlCloudRegionMap[u.Host]; ok {
+		loc.IsAWS = true
+		loc.CloudRegion = cloudRegion
+		return loc, nil
+	}
+	for _, e := range genericEndpoints {
+		if e.Host == u.Host {
+			loc.Endpoint = e.Host
+			loc.Region = e.Region
+			loc.AccessKeyEnvVar = e.AccessKeyEnvVar
+			loc.SecretKeyEnvVar = e.SecretKeyEnvVar
+			loc.PathStyle = e.PathStyle
+			loc.SSL = e.SSL
+			return loc, nil
+		}
 	}
-	return
+	return nil, fmt.Errorf("Unable to determine AWS Region or a configured S3-compatible endpoint from Host: %v", u.Host)
 }
 
 func stringToPtr(s string) *string {
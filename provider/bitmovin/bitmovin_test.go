@@ -0,0 +1,70 @@
+package bitmovin
+
+import (
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+)
+
+func TestCreateVideoPresetH264(t *testing.T) {
+	var tests = []struct {
+		preset      db.Preset
+		shouldError bool
+	}{
+		{db.Preset{Video: db.VideoPreset{Profile: "high", ProfileLevel: "3.1", Bitrate: "1000000"}}, false},
+		{db.Preset{Video: db.VideoPreset{Profile: "main", ProfileLevel: "4", Bitrate: "1000000"}}, false},
+		{db.Preset{Video: db.VideoPreset{Profile: "weird", ProfileLevel: "3.1", Bitrate: "1000000"}}, true},
+		{db.Preset{Video: db.VideoPreset{Profile: "high", ProfileLevel: "9.9", Bitrate: "1000000"}}, true},
+	}
+	p := &bitmovinProvider{}
+	for _, test := range tests {
+		_, err := p.createVideoPreset(test.preset)
+		if test.shouldError && err == nil {
+			t.Errorf("expected error for preset %+v, got none", test.preset)
+		}
+		if !test.shouldError && err != nil {
+			t.Errorf("unexpected error for preset %+v: %v", test.preset, err)
+		}
+	}
+}
+
+func TestCreateH265VideoPreset(t *testing.T) {
+	var tests = []struct {
+		preset      db.Preset
+		shouldError bool
+	}{
+		{db.Preset{Video: db.VideoPreset{Profile: "main", ProfileLevel: "3.1", Bitrate: "1000000"}}, false},
+		{db.Preset{Video: db.VideoPreset{Profile: "main10", ProfileLevel: "4", Bitrate: "1000000"}}, false},
+		{db.Preset{Video: db.VideoPreset{Profile: "mainstillpicture", ProfileLevel: "4", Bitrate: "1000000"}}, false},
+		{db.Preset{Video: db.VideoPreset{Profile: "weird", ProfileLevel: "3.1", Bitrate: "1000000"}}, true},
+		{db.Preset{Video: db.VideoPreset{Profile: "main", ProfileLevel: "9.9", Bitrate: "1000000"}}, true},
+	}
+	p := &bitmovinProvider{}
+	for _, test := range tests {
+		_, err := p.createH265VideoPreset(test.preset)
+		if test.shouldError && err == nil {
+			t.Errorf("expected error for preset %+v, got none", test.preset)
+		}
+		if !test.shouldError && err != nil {
+			t.Errorf("unexpected error for preset %+v: %v", test.preset, err)
+		}
+	}
+}
+
+func TestSplitPresetID(t *testing.T) {
+	var tests = []struct {
+		presetID      string
+		expectedCodec string
+		expectedID    string
+	}{
+		{"h264:abc123", "h264", "abc123"},
+		{"h265:abc123", "h265", "abc123"},
+		{"abc123", "h264", "abc123"},
+	}
+	for _, test := range tests {
+		codec, id := splitPresetID(test.presetID)
+		if codec != test.expectedCodec || id != test.expectedID {
+			t.Errorf("splitPresetID(%q) = (%q, %q), expected (%q, %q)", test.presetID, codec, id, test.expectedCodec, test.expectedID)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package bitmovin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bitmovin/bitmovin-go/bitmovin"
+	"github.com/bitmovin/bitmovin-go/bitmovintypes"
+	"github.com/bitmovin/bitmovin-go/models"
+	"github.com/bitmovin/bitmovin-go/services"
+	"github.com/bitmovin/video-transcoding-api/config"
+)
+
+// legacyBitmovinClient implements bitmovinClient on top of the deprecated
+// bitmovin-go SDK, preserving the exact behavior bitmovinProvider had before
+// bitmovinClient existed.
+type legacyBitmovinClient struct {
+	client *bitmovin.Bitmovin
+	config *config.Bitmovin
+}
+
+func newLegacyBitmovinClient(cfg *config.Bitmovin) (bitmovinClient, error) {
+	client := bitmovin.NewBitmovin(cfg.APIKey, cfg.Endpoint, int64(cfg.Timeout))
+	return &legacyBitmovinClient{client: client, config: cfg}, nil
+}
+
+func (c *legacyBitmovinClient) FindOrCreateAACConfig(bitrate int64) (string, error) {
+	aac := services.NewAACCodecConfigurationService(c.client)
+	response, err := aac.List(0, 1)
+	if err != nil {
+		return "", err
+	}
+	if response.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	totalCount := *response.Data.Result.TotalCount
+	response, err = aac.List(0, totalCount-1)
+	if err != nil {
+		return "", err
+	}
+	if response.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	for _, cfg := range response.Data.Result.Items {
+		if *cfg.Bitrate == bitrate {
+			return *cfg.ID, nil
+		}
+	}
+	resp, err := aac.Create(&models.AACCodecConfiguration{
+		Bitrate:      intToPtr(bitrate),
+		SamplingRate: floatToPtr(48000.0),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) RetrieveAACConfig(id string) (*models.AACCodecConfiguration, error) {
+	response, err := services.NewAACCodecConfigurationService(c.client).Retrieve(id)
+	if err != nil {
+		return nil, err
+	}
+	if response.Status == "ERROR" {
+		return nil, errors.New("")
+	}
+	return &response.Data.Result, nil
+}
+
+func (c *legacyBitmovinClient) CreateH264Config(cfg *models.H264CodecConfiguration) (string, error) {
+	resp, err := services.NewH264CodecConfigurationService(c.client).Create(cfg)
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) RetrieveH264Config(id string) (*models.H264CodecConfiguration, error) {
+	response, err := services.NewH264CodecConfigurationService(c.client).Retrieve(id)
+	if err != nil {
+		return nil, err
+	}
+	if response.Status == "ERROR" {
+		return nil, errors.New("")
+	}
+	return &response.Data.Result, nil
+}
+
+func (c *legacyBitmovinClient) DeleteH264Config(id string) error {
+	response, err := services.NewH264CodecConfigurationService(c.client).Delete(id)
+	if err != nil {
+		return err
+	}
+	if response.Status == "ERROR" {
+		return errors.New("")
+	}
+	return nil
+}
+
+func (c *legacyBitmovinClient) CreateH265Config(cfg *models.H265CodecConfiguration) (string, error) {
+	resp, err := services.NewH265CodecConfigurationService(c.client).Create(cfg)
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) RetrieveH265Config(id string) (*models.H265CodecConfiguration, error) {
+	response, err := services.NewH265CodecConfigurationService(c.client).Retrieve(id)
+	if err != nil {
+		return nil, err
+	}
+	if response.Status == "ERROR" {
+		return nil, errors.New("")
+	}
+	return &response.Data.Result, nil
+}
+
+func (c *legacyBitmovinClient) DeleteH265Config(id string) error {
+	response, err := services.NewH265CodecConfigurationService(c.client).Delete(id)
+	if err != nil {
+		return err
+	}
+	if response.Status == "ERROR" {
+		return errors.New("")
+	}
+	return nil
+}
+
+// CreateS3Input builds an AWS-native S3Input when loc points at a recognised
+// AWS endpoint and a GenericS3Input (MinIO, Wasabi, DigitalOcean Spaces, GCS
+// interop, ...) otherwise.
+func (c *legacyBitmovinClient) CreateS3Input(loc *s3Location) (string, error) {
+	if loc.IsAWS {
+		resp, err := services.NewS3InputService(c.client).Create(&models.S3Input{
+			BucketName:  stringToPtr(loc.BucketName),
+			AccessKey:   stringToPtr(c.config.AccessKeyID),
+			SecretKey:   stringToPtr(c.config.SecretAccessKey),
+			CloudRegion: loc.CloudRegion,
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp.Status == "ERROR" {
+			return "", errors.New("")
+		}
+		return *resp.Data.Result.ID, nil
+	}
+	resp, err := services.NewGenericS3InputService(c.client).Create(&models.GenericS3Input{
+		Host:            stringToPtr(loc.Endpoint),
+		BucketName:      stringToPtr(loc.BucketName),
+		AccessKey:       stringToPtr(os.Getenv(loc.AccessKeyEnvVar)),
+		SecretKey:       stringToPtr(os.Getenv(loc.SecretKeyEnvVar)),
+		CloudRegion:     loc.Region,
+		PathStyleAccess: boolToPtr(loc.PathStyle),
+		SSL:             boolToPtr(loc.SSL),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+// CreateS3Output mirrors CreateS3Input for output resources.
+func (c *legacyBitmovinClient) CreateS3Output(loc *s3Location) (string, error) {
+	if loc.IsAWS {
+		resp, err := services.NewS3OutputService(c.client).Create(&models.S3Output{
+			BucketName:  stringToPtr(loc.BucketName),
+			AccessKey:   stringToPtr(c.config.AccessKeyID),
+			SecretKey:   stringToPtr(c.config.SecretAccessKey),
+			CloudRegion: loc.CloudRegion,
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp.Status == "ERROR" {
+			return "", errors.New("")
+		}
+		return *resp.Data.Result.ID, nil
+	}
+	resp, err := services.NewGenericS3OutputService(c.client).Create(&models.GenericS3Output{
+		Host:            stringToPtr(loc.Endpoint),
+		BucketName:      stringToPtr(loc.BucketName),
+		AccessKey:       stringToPtr(os.Getenv(loc.AccessKeyEnvVar)),
+		SecretKey:       stringToPtr(os.Getenv(loc.SecretKeyEnvVar)),
+		CloudRegion:     loc.Region,
+		PathStyleAccess: boolToPtr(loc.PathStyle),
+		SSL:             boolToPtr(loc.SSL),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) CreateEncoding(name string) (string, error) {
+	resp, err := services.NewEncodingService(c.client).Create(&models.Encoding{Name: stringToPtr(name)})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) createStream(encodingID, inputID, inputPath, codecConfigID string) (string, error) {
+	resp, err := services.NewStreamService(c.client).Create(encodingID, &models.Stream{
+		CodecConfigurationID: stringToPtr(codecConfigID),
+		InputStreams: []models.StreamInput{{
+			InputID:       stringToPtr(inputID),
+			InputPath:     stringToPtr(inputPath),
+			SelectionMode: bitmovintypes.StreamSelectionModeAuto,
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) CreateVideoStream(encodingID, inputID, inputPath, codecConfigID string) (string, error) {
+	return c.createStream(encodingID, inputID, inputPath, codecConfigID)
+}
+
+func (c *legacyBitmovinClient) CreateAudioStream(encodingID, inputID, inputPath, codecConfigID string) (string, error) {
+	return c.createStream(encodingID, inputID, inputPath, codecConfigID)
+}
+
+func (c *legacyBitmovinClient) CreateMP4Muxing(encodingID, filename, outputID, outputPath string, streamIDs []string) error {
+	streams := make([]models.MuxingStream, len(streamIDs))
+	for i, id := range streamIDs {
+		streams[i] = models.MuxingStream{StreamID: stringToPtr(id)}
+	}
+	resp, err := services.NewMP4MuxingService(c.client).Create(encodingID, &models.MP4Muxing{
+		Filename: stringToPtr(filename),
+		Streams:  streams,
+		Outputs:  []models.Output{publicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Status == "ERROR" {
+		return errors.New("")
+	}
+	return nil
+}
+
+func (c *legacyBitmovinClient) CreateTSMuxing(encodingID, streamID, outputID, outputPath string, segmentDuration float64) (string, error) {
+	resp, err := services.NewTSMuxingService(c.client).Create(encodingID, &models.TSMuxing{
+		SegmentLength: floatToPtr(segmentDuration),
+		Streams:       []models.MuxingStream{{StreamID: stringToPtr(streamID)}},
+		Outputs:       []models.Output{publicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) CreateFMP4Muxing(encodingID, streamID, outputID, outputPath string, segmentDuration float64) (string, error) {
+	resp, err := services.NewFMP4MuxingService(c.client).Create(encodingID, &models.FMP4Muxing{
+		SegmentLength: floatToPtr(segmentDuration),
+		Streams:       []models.MuxingStream{{StreamID: stringToPtr(streamID)}},
+		Outputs:       []models.Output{publicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	return *resp.Data.Result.ID, nil
+}
+
+func (c *legacyBitmovinClient) SetEncodingCustomData(encodingID string, customData map[string]interface{}) error {
+	resp, err := services.NewEncodingService(c.client).Update(encodingID, &models.Encoding{CustomData: customData})
+	if err != nil {
+		return err
+	}
+	if resp.Status == "ERROR" {
+		return errors.New("")
+	}
+	return nil
+}
+
+func (c *legacyBitmovinClient) EncodingCustomData(encodingID string) (map[string]interface{}, error) {
+	resp, err := services.NewEncodingService(c.client).Retrieve(encodingID)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == "ERROR" {
+		return nil, errors.New("")
+	}
+	return resp.Data.Result.CustomData, nil
+}
+
+func (c *legacyBitmovinClient) StartEncoding(encodingID string) error {
+	resp, err := services.NewEncodingService(c.client).Start(encodingID)
+	if err != nil {
+		return err
+	}
+	if resp.Status == "ERROR" {
+		return errors.New("")
+	}
+	return nil
+}
+
+func (c *legacyBitmovinClient) StopEncoding(encodingID string) error {
+	resp, err := services.NewEncodingService(c.client).Stop(encodingID)
+	if err != nil {
+		return err
+	}
+	if resp.Status == "ERROR" {
+		return errors.New("")
+	}
+	return nil
+}
+
+func (c *legacyBitmovinClient) EncodingStatus(encodingID string) (bitmovintypes.Status, float64, error) {
+	resp, err := services.NewEncodingService(c.client).RetrieveStatus(encodingID)
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.Status == "ERROR" {
+		return "", 0, errors.New("")
+	}
+	return resp.Data.Result.Status, resp.Data.Result.Progress, nil
+}
+
+func (c *legacyBitmovinClient) CreateHLSManifest(outputID, outputPath, audioMuxingID string, renditions []manifestRendition) (string, error) {
+	hlsService := services.NewHlsManifestService(c.client)
+	resp, err := hlsService.Create(&models.HlsManifest{
+		ManifestName: stringToPtr("master.m3u8"),
+		Outputs:      []models.Output{publicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	manifestID := *resp.Data.Result.ID
+	for _, r := range renditions {
+		if _, err := hlsService.Streams.Create(manifestID, &models.StreamItem{
+			MuxingID: stringToPtr(r.TSMuxingID),
+			Audio:    stringToPtr("audio"),
+		}); err != nil {
+			return "", err
+		}
+	}
+	if _, err := hlsService.AudioMedia.Create(manifestID, &models.AudioMedia{
+		GroupID: stringToPtr("audio"),
+		Streams: []models.MuxingStream{{StreamID: stringToPtr(audioMuxingID)}},
+	}); err != nil {
+		return "", err
+	}
+	return manifestID, nil
+}
+
+func (c *legacyBitmovinClient) CreateDASHManifest(outputID, outputPath, audioMuxingID string, renditions []manifestRendition) (string, error) {
+	dashService := services.NewDashManifestService(c.client)
+	resp, err := dashService.Create(&models.DashManifest{
+		ManifestName: stringToPtr("stream.mpd"),
+		Outputs:      []models.Output{publicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Status == "ERROR" {
+		return "", errors.New("")
+	}
+	manifestID := *resp.Data.Result.ID
+
+	periodResp, err := dashService.Periods.Create(manifestID, &models.Period{})
+	if err != nil {
+		return "", err
+	}
+	periodID := *periodResp.Data.Result.ID
+
+	videoSetResp, err := dashService.VideoAdaptationSets.Create(manifestID, periodID, &models.VideoAdaptationSet{})
+	if err != nil {
+		return "", err
+	}
+	videoSetID := *videoSetResp.Data.Result.ID
+	for _, r := range renditions {
+		if _, err := dashService.FMP4Representations.Create(manifestID, periodID, videoSetID, &models.FMP4Representation{MuxingID: stringToPtr(r.FMP4MuxingID)}); err != nil {
+			return "", err
+		}
+	}
+
+	audioSetResp, err := dashService.AudioAdaptationSets.Create(manifestID, periodID, &models.AudioAdaptationSet{})
+	if err != nil {
+		return "", err
+	}
+	if _, err := dashService.FMP4Representations.Create(manifestID, periodID, *audioSetResp.Data.Result.ID, &models.FMP4Representation{MuxingID: stringToPtr(audioMuxingID)}); err != nil {
+		return "", err
+	}
+	return manifestID, nil
+}
+
+// AwaitManifest starts manifest generation and polls it to completion.
+// Manifest generation is quick relative to encoding, so a short bounded
+// poll is sufficient instead of returning control to the caller.
+func (c *legacyBitmovinClient) AwaitManifest(manifestID string, kind manifestKind) error {
+	service := c.manifestService(kind)
+	if _, err := service.Start(manifestID); err != nil {
+		return err
+	}
+	for i := 0; i < 30; i++ {
+		statusResp, err := service.RetrieveStatus(manifestID)
+		if err != nil {
+			return err
+		}
+		switch statusResp.Data.Result.Status {
+		case bitmovintypes.StatusFinished:
+			return nil
+		case bitmovintypes.StatusError:
+			return fmt.Errorf("manifest %v generation failed", manifestID)
+		}
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("timed out waiting for manifest %v to generate", manifestID)
+}
+
+func (c *legacyBitmovinClient) manifestService(kind manifestKind) interface {
+	Start(string) (models.BitmovinResponse, error)
+	RetrieveStatus(string) (models.ManifestStatusResponse, error)
+} {
+	if kind == manifestKindDASH {
+		return services.NewDashManifestService(c.client).BaseService
+	}
+	return services.NewHlsManifestService(c.client).BaseService
+}
+
+func (c *legacyBitmovinClient) Healthcheck() error {
+	response, err := services.NewAACCodecConfigurationService(c.client).List(0, 1)
+	if err != nil {
+		return err
+	}
+	if response.Status == "ERROR" {
+		return errors.New("bitmovin Healthcheck failed")
+	}
+	return nil
+}
+
+// publicOutput builds the publicly-readable S3 Output descriptor every
+// muxing/manifest in this package writes through.
+func publicOutput(outputID, outputPath string) models.Output {
+	return models.Output{
+		OutputID:   stringToPtr(outputID),
+		OutputPath: stringToPtr(outputPath),
+		ACL:        []models.ACLEntry{{Permission: bitmovintypes.ACLPermissionPublicRead}},
+	}
+}
@@ -0,0 +1,389 @@
+package bitmovin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bitmovin/bitmovin-api-sdk-go"
+	"github.com/bitmovin/bitmovin-api-sdk-go/model"
+	"github.com/bitmovin/bitmovin-go/bitmovintypes"
+	"github.com/bitmovin/bitmovin-go/models"
+	"github.com/bitmovin/video-transcoding-api/config"
+)
+
+// v2BitmovinClient implements bitmovinClient on top of bitmovin-api-sdk-go,
+// the SDK Bitmovin recommends over the deprecated bitmovin-go client used by
+// legacyBitmovinClient. It's selected via config.Bitmovin.SDKVersion = "v2".
+// That SDK also covers features the legacy SDK never got, such as per-title
+// encoding, CMAF and DRM; bitmovinClient has no operations for them.
+type v2BitmovinClient struct {
+	api    *bitmovinapi.BitmovinApi
+	config *config.Bitmovin
+}
+
+func newV2BitmovinClient(cfg *config.Bitmovin) (bitmovinClient, error) {
+	api, err := bitmovinapi.NewBitmovinApiWithApiKey(cfg.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	return &v2BitmovinClient{api: api, config: cfg}, nil
+}
+
+func (c *v2BitmovinClient) FindOrCreateAACConfig(bitrate int64) (string, error) {
+	configs, err := c.api.Encoding.CodecConfigurations.Aac.List(nil)
+	if err != nil {
+		return "", err
+	}
+	for _, cfg := range configs.Items {
+		if cfg.Bitrate == bitrate {
+			return cfg.Id, nil
+		}
+	}
+	created, err := c.api.Encoding.CodecConfigurations.Aac.Create(model.AacAudioConfiguration{
+		Bitrate:      bitrate,
+		SamplingRate: 48000.0,
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) RetrieveAACConfig(id string) (*models.AACCodecConfiguration, error) {
+	cfg, err := c.api.Encoding.CodecConfigurations.Aac.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AACCodecConfiguration{
+		ID:           stringToPtr(cfg.Id),
+		Bitrate:      intToPtr(cfg.Bitrate),
+		SamplingRate: floatToPtr(cfg.SamplingRate),
+	}, nil
+}
+
+func (c *v2BitmovinClient) CreateH264Config(cfg *models.H264CodecConfiguration) (string, error) {
+	created, err := c.api.Encoding.CodecConfigurations.H264.Create(model.H264VideoConfiguration{
+		Profile:    model.ProfileH264(cfg.Profile),
+		Level:      model.LevelH264(cfg.Level),
+		Width:      derefInt(cfg.Width),
+		Height:     derefInt(cfg.Height),
+		Bitrate:    derefInt(cfg.Bitrate),
+		MaxGop:     derefInt(cfg.MaxGOP),
+		CustomData: cfg.CustomData,
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) RetrieveH264Config(id string) (*models.H264CodecConfiguration, error) {
+	cfg, err := c.api.Encoding.CodecConfigurations.H264.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.H264CodecConfiguration{
+		ID:         stringToPtr(cfg.Id),
+		Profile:    bitmovintypes.H264Profile(cfg.Profile),
+		Level:      bitmovintypes.H264Level(cfg.Level),
+		Width:      intToPtr(cfg.Width),
+		Height:     intToPtr(cfg.Height),
+		Bitrate:    intToPtr(cfg.Bitrate),
+		MaxGOP:     intToPtr(cfg.MaxGop),
+		CustomData: cfg.CustomData,
+	}, nil
+}
+
+func (c *v2BitmovinClient) DeleteH264Config(id string) error {
+	_, err := c.api.Encoding.CodecConfigurations.H264.Delete(id)
+	return err
+}
+
+func (c *v2BitmovinClient) CreateH265Config(cfg *models.H265CodecConfiguration) (string, error) {
+	created, err := c.api.Encoding.CodecConfigurations.H265.Create(model.H265VideoConfiguration{
+		Profile:    model.ProfileH265(cfg.Profile),
+		Level:      model.LevelH265(cfg.Level),
+		Width:      derefInt(cfg.Width),
+		Height:     derefInt(cfg.Height),
+		Bitrate:    derefInt(cfg.Bitrate),
+		MaxGop:     derefInt(cfg.MaxGOP),
+		CustomData: cfg.CustomData,
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) RetrieveH265Config(id string) (*models.H265CodecConfiguration, error) {
+	cfg, err := c.api.Encoding.CodecConfigurations.H265.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.H265CodecConfiguration{
+		ID:         stringToPtr(cfg.Id),
+		Profile:    bitmovintypes.H265Profile(cfg.Profile),
+		Level:      bitmovintypes.H265Level(cfg.Level),
+		Width:      intToPtr(cfg.Width),
+		Height:     intToPtr(cfg.Height),
+		Bitrate:    intToPtr(cfg.Bitrate),
+		MaxGOP:     intToPtr(cfg.MaxGop),
+		CustomData: cfg.CustomData,
+	}, nil
+}
+
+func (c *v2BitmovinClient) DeleteH265Config(id string) error {
+	_, err := c.api.Encoding.CodecConfigurations.H265.Delete(id)
+	return err
+}
+
+func (c *v2BitmovinClient) CreateS3Input(loc *s3Location) (string, error) {
+	if loc.IsAWS {
+		created, err := c.api.Encoding.Inputs.S3.Create(model.S3Input{
+			BucketName:  loc.BucketName,
+			AccessKey:   c.config.AccessKeyID,
+			SecretKey:   c.config.SecretAccessKey,
+			CloudRegion: model.AwsCloudRegion(loc.CloudRegion),
+		})
+		if err != nil {
+			return "", err
+		}
+		return created.Id, nil
+	}
+	created, err := c.api.Encoding.Inputs.GenericS3.Create(model.GenericS3Input{
+		Host:            loc.Endpoint,
+		BucketName:      loc.BucketName,
+		AccessKey:       os.Getenv(loc.AccessKeyEnvVar),
+		SecretKey:       os.Getenv(loc.SecretKeyEnvVar),
+		SSL:             loc.SSL,
+		PathStyleAccess: loc.PathStyle,
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) CreateS3Output(loc *s3Location) (string, error) {
+	if loc.IsAWS {
+		created, err := c.api.Encoding.Outputs.S3.Create(model.S3Output{
+			BucketName:  loc.BucketName,
+			AccessKey:   c.config.AccessKeyID,
+			SecretKey:   c.config.SecretAccessKey,
+			CloudRegion: model.AwsCloudRegion(loc.CloudRegion),
+		})
+		if err != nil {
+			return "", err
+		}
+		return created.Id, nil
+	}
+	created, err := c.api.Encoding.Outputs.GenericS3.Create(model.GenericS3Output{
+		Host:            loc.Endpoint,
+		BucketName:      loc.BucketName,
+		AccessKey:       os.Getenv(loc.AccessKeyEnvVar),
+		SecretKey:       os.Getenv(loc.SecretKeyEnvVar),
+		SSL:             loc.SSL,
+		PathStyleAccess: loc.PathStyle,
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) CreateEncoding(name string) (string, error) {
+	created, err := c.api.Encoding.Encodings.Create(model.Encoding{Name: name})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) createStream(encodingID, inputID, inputPath, codecConfigID string) (string, error) {
+	created, err := c.api.Encoding.Encodings.Streams.Create(encodingID, model.Stream{
+		CodecConfigId: codecConfigID,
+		InputStreams: []model.StreamInput{{
+			InputId:       inputID,
+			InputPath:     inputPath,
+			SelectionMode: model.StreamSelectionMode_AUTO,
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) CreateVideoStream(encodingID, inputID, inputPath, codecConfigID string) (string, error) {
+	return c.createStream(encodingID, inputID, inputPath, codecConfigID)
+}
+
+func (c *v2BitmovinClient) CreateAudioStream(encodingID, inputID, inputPath, codecConfigID string) (string, error) {
+	return c.createStream(encodingID, inputID, inputPath, codecConfigID)
+}
+
+func (c *v2BitmovinClient) CreateMP4Muxing(encodingID, filename, outputID, outputPath string, streamIDs []string) error {
+	streams := make([]model.MuxingStream, len(streamIDs))
+	for i, id := range streamIDs {
+		streams[i] = model.MuxingStream{StreamId: id}
+	}
+	_, err := c.api.Encoding.Encodings.Muxings.Mp4.Create(encodingID, model.Mp4Muxing{
+		Filename: filename,
+		Streams:  streams,
+		Outputs:  []model.EncodingOutput{v2PublicOutput(outputID, outputPath)},
+	})
+	return err
+}
+
+func (c *v2BitmovinClient) CreateTSMuxing(encodingID, streamID, outputID, outputPath string, segmentDuration float64) (string, error) {
+	created, err := c.api.Encoding.Encodings.Muxings.Ts.Create(encodingID, model.TsMuxing{
+		SegmentLength: segmentDuration,
+		Streams:       []model.MuxingStream{{StreamId: streamID}},
+		Outputs:       []model.EncodingOutput{v2PublicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) CreateFMP4Muxing(encodingID, streamID, outputID, outputPath string, segmentDuration float64) (string, error) {
+	created, err := c.api.Encoding.Encodings.Muxings.Fmp4.Create(encodingID, model.Fmp4Muxing{
+		SegmentLength: segmentDuration,
+		Streams:       []model.MuxingStream{{StreamId: streamID}},
+		Outputs:       []model.EncodingOutput{v2PublicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (c *v2BitmovinClient) SetEncodingCustomData(encodingID string, customData map[string]interface{}) error {
+	_, err := c.api.Encoding.Encodings.CustomData.Update(encodingID, model.CustomDataContainer{CustomData: customData})
+	return err
+}
+
+func (c *v2BitmovinClient) EncodingCustomData(encodingID string) (map[string]interface{}, error) {
+	data, err := c.api.Encoding.Encodings.CustomData.Get(encodingID)
+	if err != nil {
+		return nil, err
+	}
+	return data.CustomData, nil
+}
+
+func (c *v2BitmovinClient) StartEncoding(encodingID string) error {
+	_, err := c.api.Encoding.Encodings.Start(encodingID, model.StartEncodingRequest{})
+	return err
+}
+
+func (c *v2BitmovinClient) StopEncoding(encodingID string) error {
+	_, err := c.api.Encoding.Encodings.Stop(encodingID)
+	return err
+}
+
+func (c *v2BitmovinClient) EncodingStatus(encodingID string) (bitmovintypes.Status, float64, error) {
+	status, err := c.api.Encoding.Encodings.Status(encodingID)
+	if err != nil {
+		return "", 0, err
+	}
+	return bitmovintypes.Status(status.Status), status.Progress, nil
+}
+
+func (c *v2BitmovinClient) CreateHLSManifest(outputID, outputPath, audioMuxingID string, renditions []manifestRendition) (string, error) {
+	manifest, err := c.api.Encoding.Manifests.Hls.Create(model.HlsManifest{
+		ManifestName: "master.m3u8",
+		Outputs:      []model.EncodingOutput{v2PublicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, r := range renditions {
+		if _, err := c.api.Encoding.Manifests.Hls.Streams.Create(manifest.Id, model.StreamInfo{
+			Audio:    "audio",
+			MuxingId: r.TSMuxingID,
+		}); err != nil {
+			return "", err
+		}
+	}
+	if _, err := c.api.Encoding.Manifests.Hls.Media.Audio.Create(manifest.Id, model.AudioMediaInfo{
+		GroupId:  "audio",
+		MuxingId: audioMuxingID,
+	}); err != nil {
+		return "", err
+	}
+	return manifest.Id, nil
+}
+
+func (c *v2BitmovinClient) CreateDASHManifest(outputID, outputPath, audioMuxingID string, renditions []manifestRendition) (string, error) {
+	manifest, err := c.api.Encoding.Manifests.Dash.Create(model.DashManifest{
+		ManifestName: "stream.mpd",
+		Outputs:      []model.EncodingOutput{v2PublicOutput(outputID, outputPath)},
+	})
+	if err != nil {
+		return "", err
+	}
+	period, err := c.api.Encoding.Manifests.Dash.Periods.Create(manifest.Id, model.Period{})
+	if err != nil {
+		return "", err
+	}
+	videoSet, err := c.api.Encoding.Manifests.Dash.Periods.Videoadaptationsets.Create(manifest.Id, period.Id, model.VideoAdaptationSet{})
+	if err != nil {
+		return "", err
+	}
+	for _, r := range renditions {
+		if _, err := c.api.Encoding.Manifests.Dash.Periods.Videoadaptationsets.Representations.Fmp4.Create(manifest.Id, period.Id, videoSet.Id, model.DashFmp4Representation{MuxingId: r.FMP4MuxingID}); err != nil {
+			return "", err
+		}
+	}
+	audioSet, err := c.api.Encoding.Manifests.Dash.Periods.Audioadaptationsets.Create(manifest.Id, period.Id, model.AudioAdaptationSet{})
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.api.Encoding.Manifests.Dash.Periods.Audioadaptationsets.Representations.Fmp4.Create(manifest.Id, period.Id, audioSet.Id, model.DashFmp4Representation{MuxingId: audioMuxingID}); err != nil {
+		return "", err
+	}
+	return manifest.Id, nil
+}
+
+func (c *v2BitmovinClient) AwaitManifest(manifestID string, kind manifestKind) error {
+	start, status := c.api.Encoding.Manifests.Hls.Start, c.api.Encoding.Manifests.Hls.Status
+	if kind == manifestKindDASH {
+		start, status = c.api.Encoding.Manifests.Dash.Start, c.api.Encoding.Manifests.Dash.Status
+	}
+	if _, err := start(manifestID, model.StartManifestRequest{}); err != nil {
+		return err
+	}
+	for i := 0; i < 30; i++ {
+		s, err := status(manifestID)
+		if err != nil {
+			return err
+		}
+		switch s.Status {
+		case model.Status_FINISHED:
+			return nil
+		case model.Status_ERROR:
+			return fmt.Errorf("manifest %v generation failed", manifestID)
+		}
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("timed out waiting for manifest %v to generate", manifestID)
+}
+
+func (c *v2BitmovinClient) Healthcheck() error {
+	_, err := c.api.Encoding.CodecConfigurations.Aac.List(&bitmovinapi.AacCodecConfigurationListQueryParams{Limit: 1})
+	if err != nil {
+		return errors.New("bitmovin Healthcheck failed")
+	}
+	return nil
+}
+
+func v2PublicOutput(outputID, outputPath string) model.EncodingOutput {
+	return model.EncodingOutput{
+		OutputId:   outputID,
+		OutputPath: outputPath,
+		Acl:        []model.AclEntry{{Permission: model.AclPermission_PUBLIC_READ}},
+	}
+}